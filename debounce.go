@@ -0,0 +1,193 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DebouncedWatcher wraps a Watcher, coalescing bursts of events on the same
+// path within a sliding window into a single Event whose Op is the
+// bitwise-OR of everything observed, except that a Remove drops any Write
+// pending before it. It exists for consumers - config
+// reloaders, CNI conf syncers - that would otherwise see a Create, Write,
+// Chmod and Rename in quick succession from one editor save and want to
+// react to it once.
+type DebouncedWatcher struct {
+	Events chan Event
+	Errors chan error
+
+	w      *Watcher
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingEvent
+	seq     int
+
+	flush    chan chan struct{}
+	done     chan struct{}
+	doneResp chan struct{}
+}
+
+type pendingEvent struct {
+	op    Op
+	seq   int
+	timer *time.Timer
+}
+
+// NewDebouncedWatcher wraps w so that events on the same path arriving
+// within window of each other are merged into one. The returned watcher
+// takes ownership of w: closing it also closes w.
+func NewDebouncedWatcher(w *Watcher, window time.Duration) *DebouncedWatcher {
+	d := &DebouncedWatcher{
+		Events:   make(chan Event),
+		Errors:   make(chan error),
+		w:        w,
+		window:   window,
+		pending:  make(map[string]*pendingEvent),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		doneResp: make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+// Flush immediately emits every currently-buffered event, in the order it
+// was first observed, instead of waiting out the remainder of its window.
+func (d *DebouncedWatcher) Flush() {
+	ack := make(chan struct{})
+	select {
+	case d.flush <- ack:
+		<-ack
+	case <-d.done:
+	}
+}
+
+// Close stops the debouncer and the underlying Watcher.
+func (d *DebouncedWatcher) Close() error {
+	select {
+	case <-d.done:
+		return nil
+	default:
+	}
+	close(d.done)
+	<-d.doneResp
+	return d.w.Close()
+}
+
+func (d *DebouncedWatcher) loop() {
+	defer close(d.doneResp)
+	defer close(d.Errors)
+	defer close(d.Events)
+
+	events := d.w.Events
+	errs := d.w.Errors
+	fire := make(chan string)
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			d.merge(e, fire)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			select {
+			case d.Errors <- err:
+			case <-d.done:
+				return
+			}
+		case path := <-fire:
+			d.emit(path)
+		case ack := <-d.flush:
+			d.flushAll()
+			close(ack)
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// merge folds e into the pending event for e.Name and (re)arms its window
+// timer.
+func (d *DebouncedWatcher) merge(e Event, fire chan<- string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.pending[e.Name]
+	if !ok {
+		d.seq++
+		p = &pendingEvent{seq: d.seq}
+		d.pending[e.Name] = p
+	}
+
+	if e.Op&Remove != 0 {
+		// A Remove immediately following a still-pending Write means the
+		// write never produced a file worth reloading - drop it rather than
+		// reporting Write|Remove.
+		p.op &^= Write
+		p.op |= Remove
+	} else {
+		p.op |= e.Op
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	path := e.Name
+	p.timer = time.AfterFunc(d.window, func() {
+		select {
+		case fire <- path:
+		case <-d.done:
+		}
+	})
+}
+
+// emit delivers the merged event for path, if one is still pending.
+func (d *DebouncedWatcher) emit(path string) {
+	d.mu.Lock()
+	p, ok := d.pending[path]
+	if ok {
+		delete(d.pending, path)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case d.Events <- Event{path, p.op}:
+	case <-d.done:
+	}
+}
+
+// flushAll emits every pending event, oldest first.
+func (d *DebouncedWatcher) flushAll() {
+	d.mu.Lock()
+	type entry struct {
+		path string
+		seq  int
+	}
+	entries := make([]entry, 0, len(d.pending))
+	for path, p := range d.pending {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		entries = append(entries, entry{path, p.seq})
+	}
+	d.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	for _, en := range entries {
+		d.emit(en.path)
+	}
+}
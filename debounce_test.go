@@ -0,0 +1,86 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestDebouncer builds a DebouncedWatcher without a backing Watcher or
+// running loop(), so merge/emit/flushAll - the pure coalescing logic - can
+// be exercised directly.
+func newTestDebouncer() *DebouncedWatcher {
+	return &DebouncedWatcher{
+		Events:  make(chan Event),
+		Errors:  make(chan error),
+		pending: make(map[string]*pendingEvent),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func TestDebouncedWatcherMergesOpsWithinWindow(t *testing.T) {
+	d := newTestDebouncer()
+	fire := make(chan string, 2)
+
+	d.merge(Event{"a", Create}, fire)
+	d.merge(Event{"a", Write}, fire)
+
+	d.mu.Lock()
+	got := d.pending["a"].op
+	d.mu.Unlock()
+
+	if want := Create | Write; got != want {
+		t.Fatalf("op = %v, want %v", got, want)
+	}
+}
+
+func TestDebouncedWatcherDropsWriteBeforeRemove(t *testing.T) {
+	d := newTestDebouncer()
+	fire := make(chan string, 2)
+
+	d.merge(Event{"a", Write}, fire)
+	d.merge(Event{"a", Remove}, fire)
+
+	d.mu.Lock()
+	got := d.pending["a"].op
+	d.mu.Unlock()
+
+	if got&Write != 0 {
+		t.Fatalf("op = %v, still has Write after a Remove", got)
+	}
+	if got&Remove == 0 {
+		t.Fatalf("op = %v, missing Remove", got)
+	}
+}
+
+func TestDebouncedWatcherFlushAllEmitsInArrivalOrder(t *testing.T) {
+	d := newTestDebouncer()
+	fire := make(chan string, 2)
+
+	d.merge(Event{"a", Write}, fire)
+	d.merge(Event{"b", Write}, fire)
+
+	order := make(chan []string, 1)
+	go func() {
+		var names []string
+		for i := 0; i < 2; i++ {
+			names = append(names, (<-d.Events).Name)
+		}
+		order <- names
+	}()
+
+	d.flushAll()
+
+	select {
+	case names := <-order:
+		if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+			t.Fatalf("emit order = %v, want [a b]", names)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for flushed events")
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import "fmt"
+
+// PathError describes a failure that occurred while fsnotify was working
+// with a specific watched path - for example a failed re-associate after a
+// rename, or a stat that raced a delete. Op is a short, lower-case verb
+// ("associate", "dissociate", "stat") describing what fsnotify was doing.
+type PathError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("fsnotify: %s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+
+// QueueOverflowError indicates the OS event source itself failed or
+// overflowed, rather than any one watched path - for example port_get
+// returning an error unrelated to Close. Consumers typically respond by
+// re-creating the Watcher, since individual watches can't be trusted after
+// this.
+type QueueOverflowError struct {
+	Err error
+}
+
+func (e *QueueOverflowError) Error() string {
+	return fmt.Sprintf("fsnotify: event queue error: %v", e.Err)
+}
+
+func (e *QueueOverflowError) Unwrap() error { return e.Err }
+
+// WatchLimitError indicates a watch could not be added because the OS-
+// imposed limit on concurrent watches has been reached.
+type WatchLimitError struct {
+	Path string
+	Err  error
+}
+
+func (e *WatchLimitError) Error() string {
+	return fmt.Sprintf("fsnotify: watch limit reached adding %s: %v", e.Path, e.Err)
+}
+
+func (e *WatchLimitError) Unwrap() error { return e.Err }
@@ -26,13 +26,16 @@ package fsnotify
 // };
 import "C"
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -41,10 +44,22 @@ type Watcher struct {
 	Events chan Event
 	Errors chan error
 
+	// MaxDepth limits how many directory levels AddRecursive descends, where
+	// 0 (the default) means no limit. It has no effect on Add.
+	MaxDepth int
+
+	// SkipFunc, if non-nil, is consulted by AddRecursive for every path it
+	// would otherwise watch; a directory for which it returns true is not
+	// watched and is not descended into.
+	SkipFunc func(path string, info os.FileInfo) bool
+
 	port C.int // solaris port for underlying FEN system
 
-	mu      sync.Mutex
-	watches map[string]*C.struct_file_obj
+	mu             sync.Mutex
+	watches        map[string]*C.struct_file_obj
+	recursiveRoots map[string]struct{}   // roots added via AddRecursive
+	globs          map[string]*globWatch // patterns added via AddGlob, keyed by pattern
+	watchErrs      map[string]chan error // per-path error channels requested via WatchErrors
 
 	done     chan struct{} // Channel for sending a "quit message" to the reader goroutine
 	doneResp chan struct{} // Channel to respond to Close
@@ -62,6 +77,9 @@ func NewWatcher() (*Watcher, error) {
 		return nil, err
 	}
 	w.watches = make(map[string]*C.struct_file_obj)
+	w.recursiveRoots = make(map[string]struct{})
+	w.globs = make(map[string]*globWatch)
+	w.watchErrs = make(map[string]chan error)
 	w.done = make(chan struct{})
 	w.doneResp = make(chan struct{})
 
@@ -69,6 +87,28 @@ func NewWatcher() (*Watcher, error) {
 	return w, nil
 }
 
+// New creates a FileWatcher backed by the native FEN Watcher. If the native
+// watcher cannot be created - for example because the system is out of FEN
+// ports, or the filesystem (NFS, FUSE) doesn't support file events - it
+// falls back to a PollingWatcher that polls every interval.
+func New(interval time.Duration) (FileWatcher, error) {
+	w, err := NewWatcher()
+	if err == nil {
+		return nativeWatcher{w}, nil
+	}
+	return NewPollingWatcher(interval), nil
+}
+
+// nativeWatcher adapts *Watcher to the FileWatcher interface; Watcher itself
+// can't implement it directly because its Events and Errors channels are
+// already exported fields.
+type nativeWatcher struct {
+	*Watcher
+}
+
+func (w nativeWatcher) Events() <-chan Event { return w.Watcher.Events }
+func (w nativeWatcher) Errors() <-chan error { return w.Watcher.Errors }
+
 // sendEvent attempts to send an event to the user, returning true if the event
 // was put in the channel successfully and false if the watcher has been closed.
 func (w *Watcher) sendEvent(e Event) (sent bool) {
@@ -82,7 +122,21 @@ func (w *Watcher) sendEvent(e Event) (sent bool) {
 
 // sendError attempts to send an event to the user, returning true if the error
 // was put in the channel successfully and false if the watcher has been closed.
+// If err carries a path (PathError or WatchLimitError), it's also delivered,
+// best-effort, to that path's WatchErrors channel, if anyone asked for one.
 func (w *Watcher) sendError(err error) (sent bool) {
+	if path := errorPath(err); path != "" {
+		w.mu.Lock()
+		ch, ok := w.watchErrs[path]
+		w.mu.Unlock()
+		if ok {
+			select {
+			case ch <- err:
+			default:
+			}
+		}
+	}
+
 	select {
 	case w.Errors <- err:
 		return true
@@ -91,6 +145,34 @@ func (w *Watcher) sendError(err error) (sent bool) {
 	}
 }
 
+// errorPath returns the path err is about, or "" if it isn't about one.
+func errorPath(err error) string {
+	switch e := err.(type) {
+	case *PathError:
+		return e.Path
+	case *WatchLimitError:
+		return e.Path
+	default:
+		return ""
+	}
+}
+
+// WatchErrors returns a channel that receives errors concerning path
+// specifically (failed re-associates, watch-limit hits), in addition to
+// their usual delivery on Errors. The channel is buffered by one; if the
+// caller isn't reading, newer path-specific errors are dropped rather than
+// blocking event delivery.
+func (w *Watcher) WatchErrors(path string) <-chan error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if ch, ok := w.watchErrs[path]; ok {
+		return ch
+	}
+	ch := make(chan error, 1)
+	w.watchErrs[path] = ch
+	return ch
+}
+
 func (w *Watcher) isClosed() bool {
 	select {
 	case <-w.done:
@@ -127,6 +209,235 @@ func (w *Watcher) Add(path string) error {
 	}
 }
 
+// AddRecursive starts watching path and, if path is a directory, every
+// subdirectory beneath it, descending at most MaxDepth levels (0 means no
+// limit) and skipping any path for which SkipFunc returns true.
+// Subdirectories created later under path are discovered and associated
+// automatically as FILE_MODIFIED events arrive for their parent.
+func (w *Watcher) AddRecursive(path string) error {
+	if w.isClosed() {
+		return errors.New("FEN watcher already closed")
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return w.associateFile(path, stat)
+	}
+
+	w.mu.Lock()
+	w.recursiveRoots[path] = struct{}{}
+	w.mu.Unlock()
+
+	return w.walkAndAssociate(path, path, nil)
+}
+
+// RemoveRecursive stops watching path and dissociates every descendant
+// watch that AddRecursive established beneath it.
+func (w *Watcher) RemoveRecursive(path string) error {
+	if w.isClosed() {
+		return errors.New("FEN watcher already closed")
+	}
+
+	return w.dissociateSubtree(path)
+}
+
+// globWatch is the bookkeeping AddGlob keeps for one pattern.
+type globWatch struct {
+	pattern   string
+	dir       string // longest static prefix directory
+	recursive bool   // pattern contains "**"
+}
+
+// AddGlob starts watching every existing file matching pattern, and
+// continues watching new files that appear and match it later. pattern
+// uses doublestar-style syntax ("cni/net.d/*.conflist", "**/*.conf");
+// internally only the longest wildcard-free prefix directory is watched
+// (recursively, if pattern contains "**"), and incoming entries are
+// filtered against pattern before being associated.
+func (w *Watcher) AddGlob(pattern string) error {
+	if w.isClosed() {
+		return errors.New("FEN watcher already closed")
+	}
+
+	g := &globWatch{
+		pattern:   pattern,
+		dir:       globPrefixDir(pattern),
+		recursive: strings.Contains(pattern, "**"),
+	}
+
+	w.mu.Lock()
+	w.globs[pattern] = g
+	w.mu.Unlock()
+
+	if g.recursive {
+		return w.walkGlobAndAssociate(g, g.dir, nil)
+	}
+
+	stat, err := os.Stat(g.dir)
+	if err != nil {
+		return err
+	}
+	if err := w.associateFile(g.dir, stat); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(g.dir)
+	if err != nil {
+		return err
+	}
+	for _, finfo := range entries {
+		p := filepath.Join(g.dir, finfo.Name())
+		if finfo.IsDir() || !globMatch(pattern, p) {
+			continue
+		}
+		if err := w.associateFile(p, finfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveGlob stops watching pattern. If pattern's prefix directory isn't
+// shared with any other registered pattern, that directory's watch (or
+// recursive watch) is torn down too.
+func (w *Watcher) RemoveGlob(pattern string) error {
+	if w.isClosed() {
+		return errors.New("FEN watcher already closed")
+	}
+
+	w.mu.Lock()
+	g, ok := w.globs[pattern]
+	delete(w.globs, pattern)
+	shared := false
+	if ok {
+		for _, other := range w.globs {
+			if other.dir == g.dir {
+				shared = true
+				break
+			}
+		}
+	}
+	w.mu.Unlock()
+
+	if !ok || shared {
+		return nil
+	}
+	if g.recursive {
+		return w.RemoveRecursive(g.dir)
+	}
+	return w.Remove(g.dir)
+}
+
+// walkGlobAndAssociate walks path, associating every directory (so
+// descendants under a "**" pattern keep being discovered) but only
+// associating - and, via onMatch, reporting - files that match g.pattern.
+func (w *Watcher) walkGlobAndAssociate(g *globWatch, path string, onMatch func(string) error) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != path && w.SkipFunc != nil && w.SkipFunc(p, info) {
+				return filepath.SkipDir
+			}
+			return w.associateFile(p, info)
+		}
+		if !globMatch(g.pattern, p) {
+			return nil
+		}
+		if err := w.associateFile(p, info); err != nil {
+			return err
+		}
+		if onMatch != nil {
+			return onMatch(p)
+		}
+		return nil
+	})
+}
+
+// globsFor returns the registered glob patterns whose watch covers path:
+// either path is itself a pattern's prefix directory, or path is a
+// descendant of a recursive pattern's prefix directory.
+func (w *Watcher) globsFor(path string) []*globWatch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var out []*globWatch
+	for _, g := range w.globs {
+		if g.dir == path {
+			out = append(out, g)
+			continue
+		}
+		if !g.recursive {
+			continue
+		}
+		if g.dir == "." {
+			out = append(out, g)
+			continue
+		}
+		if strings.HasPrefix(path, g.dir+string(filepath.Separator)) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func globMatchAny(globs []*globWatch, path string) bool {
+	for _, g := range globs {
+		if globMatch(g.pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddContext starts watching path like Add, but also ties the watch to
+// ctx's lifetime: once ctx is done, path is automatically Removed.
+func (w *Watcher) AddContext(ctx context.Context, path string) error {
+	if err := w.Add(path); err != nil {
+		return err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Remove(path)
+		case <-w.done:
+		}
+	}()
+	return nil
+}
+
+// ReadEvent reads the next event, like receiving from Events, but returns
+// ctx.Err() instead of blocking forever if ctx is done first.
+func (w *Watcher) ReadEvent(ctx context.Context) (Event, error) {
+	select {
+	case e, ok := <-w.Events:
+		if !ok {
+			return Event{}, errors.New("FEN watcher closed")
+		}
+		return e, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Run blocks until ctx is done, then closes w. It starts no event loop of
+// its own - readEvents is already running from NewWatcher - so callers can
+// tie a Watcher's lifetime to a context instead of managing Close in a
+// defer:
+//
+//	go w.Run(ctx)
+func (w *Watcher) Run(ctx context.Context) error {
+	<-ctx.Done()
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
 // Remove stops watching the the named file or directory (non-recursively).
 func (w *Watcher) Remove(path string) error {
 	if w.isClosed() {
@@ -154,6 +465,13 @@ func (w *Watcher) readEvents() {
 	defer close(w.doneResp)
 	defer close(w.Errors)
 	defer close(w.Events)
+	defer func() {
+		w.mu.Lock()
+		for _, ch := range w.watchErrs {
+			close(ch)
+		}
+		w.mu.Unlock()
+	}()
 
 	for {
 		var pevent C.port_event_t
@@ -163,8 +481,9 @@ func (w *Watcher) readEvents() {
 			if w.isClosed() {
 				return
 			}
-			// There was an error not caused by calling w.Close()
-			if !w.sendError(err) {
+			// There was an error not caused by calling w.Close(): the port
+			// itself, not any one watched path, is in trouble.
+			if !w.sendError(&QueueOverflowError{Err: err}) {
 				return
 			}
 		}
@@ -226,6 +545,13 @@ func (w *Watcher) handleEvent(obj C.uintptr_t, events C.int, finfo *C.struct_fil
 		toSend = &Event{path, Chmod}
 	case events&C.FILE_DELETE == C.FILE_DELETE:
 		w.unwatch(path)
+		if fmode.IsDir() {
+			if err := w.dissociateSubtree(path); err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+		}
 		toSend = &Event{path, Remove}
 		reRegister = false
 	case events&C.FILE_RENAME_TO == C.FILE_RENAME_TO:
@@ -242,11 +568,25 @@ func (w *Watcher) handleEvent(obj C.uintptr_t, events C.int, finfo *C.struct_fil
 		}
 		// Don't keep watching the file that was removed
 		w.unwatch(path)
+		if fmode.IsDir() {
+			if err := w.dissociateSubtree(path); err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+		}
 		reRegister = false
 	case events&C.FILE_RENAME_FROM == C.FILE_RENAME_FROM:
 		toSend = &Event{path, Rename}
 		// Don't keep watching the new file name
 		w.unwatch(path)
+		if fmode.IsDir() {
+			if err := w.dissociateSubtree(path); err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+		}
 		reRegister = false
 	default:
 		return errors.New("unknown event received")
@@ -265,7 +605,7 @@ func (w *Watcher) handleEvent(obj C.uintptr_t, events C.int, finfo *C.struct_fil
 	// continue watching the file
 	stat, err := os.Stat(path)
 	if err != nil {
-		return err
+		return &PathError{Path: path, Op: "stat", Err: err}
 	}
 	return w.associateFile(path, stat)
 }
@@ -279,25 +619,182 @@ func (w *Watcher) updateDirectory(path string) error {
 		return err
 	}
 
+	root, recursive := w.recursiveRootFor(path)
+	globs := w.globsFor(path)
+
 	for _, finfo := range files {
-		path := filepath.Join(path, finfo.Name())
-		if w.watched(path) {
+		entry := filepath.Join(path, finfo.Name())
+		if w.watched(entry) {
 			continue
 		}
 
-		err := w.associateFile(path, finfo)
-		if err != nil {
-			if !w.sendError(err) {
+		switch {
+		case len(globs) > 0:
+			if err := w.updateGlobEntry(globs, entry, finfo); err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+
+		case recursive && w.skipRecursive(root, entry, finfo):
+			// Excluded by SkipFunc or MaxDepth, as in AddRecursive's walk.
+
+		case recursive && finfo.IsDir():
+			// A new subdirectory appeared under a recursively-watched root;
+			// walk and associate it (and everything beneath it) too, so
+			// further descendants are picked up without the caller having
+			// to re-Add.
+			err := w.walkAndAssociate(root, entry, func(assoc string) error {
+				if !w.sendEvent(Event{assoc, Create}) {
+					return errors.New("FEN watcher already closed")
+				}
+				return nil
+			})
+			if err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+
+		default:
+			err := w.associateFile(entry, finfo)
+			if err != nil {
+				if !w.sendError(err) {
+					return nil
+				}
+			}
+			if !w.sendEvent(Event{entry, Create}) {
 				return nil
 			}
 		}
-		if !w.sendEvent(Event{path, Create}) {
-			return nil
+	}
+	return nil
+}
+
+// updateGlobEntry handles a newly-discovered entry under a directory that
+// one or more AddGlob patterns are watching. Non-matching files are left
+// unassociated; subdirectories of a "**" pattern are still walked, since a
+// match may appear further down even though the directory name itself
+// doesn't match.
+func (w *Watcher) updateGlobEntry(globs []*globWatch, entry string, finfo os.FileInfo) error {
+	if finfo.IsDir() {
+		for _, g := range globs {
+			if !g.recursive {
+				continue
+			}
+			err := w.walkGlobAndAssociate(g, entry, func(assoc string) error {
+				if !w.sendEvent(Event{assoc, Create}) {
+					return errors.New("FEN watcher already closed")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+
+	if !globMatchAny(globs, entry) {
+		return nil
+	}
+	if err := w.associateFile(entry, finfo); err != nil {
+		return err
+	}
+	if !w.sendEvent(Event{entry, Create}) {
+		return errors.New("FEN watcher already closed")
 	}
 	return nil
 }
 
+// walkAndAssociate walks the subtree rooted at path - honoring MaxDepth and
+// SkipFunc relative to root - and associates every file and directory it
+// finds. If onAssociate is non-nil, it is called after each successful
+// association; updateDirectory uses this to emit Create events for entries
+// discovered after the fact, while AddRecursive's initial walk leaves it
+// nil to match Add's behavior of not announcing pre-existing files.
+func (w *Watcher) walkAndAssociate(root, path string, onAssociate func(string) error) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != path && w.skipRecursive(root, p, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := w.associateFile(p, info); err != nil {
+			return err
+		}
+		if onAssociate != nil {
+			return onAssociate(p)
+		}
+		return nil
+	})
+}
+
+// skipRecursive reports whether path should be excluded from a recursive
+// walk rooted at root, per SkipFunc and MaxDepth.
+func (w *Watcher) skipRecursive(root, path string, info os.FileInfo) bool {
+	if w.SkipFunc != nil && w.SkipFunc(path, info) {
+		return true
+	}
+	if w.MaxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return strings.Count(rel, string(filepath.Separator))+1 > w.MaxDepth
+}
+
+// recursiveRootFor reports the recursive-watch root that path falls under,
+// if any.
+func (w *Watcher) recursiveRootFor(path string) (root string, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for r := range w.recursiveRoots {
+		if path == r || strings.HasPrefix(path, r+string(filepath.Separator)) {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// dissociateSubtree dissociates every watch at or beneath root, also
+// clearing root from recursiveRoots if it was one. It's used both by
+// RemoveRecursive and to clean up after a recursively-watched subdirectory
+// is renamed or removed out from under us - in the latter case root no
+// longer exists, so every descendant must still be attempted even if one
+// dissociate fails, or the survivors are stuck in w.watches forever.
+func (w *Watcher) dissociateSubtree(root string) error {
+	w.mu.Lock()
+	paths := make([]string, 0)
+	for p := range w.watches {
+		if p == root || strings.HasPrefix(p, root+string(filepath.Separator)) {
+			paths = append(paths, p)
+		}
+	}
+	delete(w.recursiveRoots, root)
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, p := range paths {
+		fobj := w.unwatch(p)
+		if fobj == nil {
+			continue
+		}
+		if _, err := C.port_dissociate(w.port, C.PORT_SOURCE_FILE, C.from_file_obj(fobj)); err != nil {
+			if firstErr == nil {
+				firstErr = &PathError{Path: p, Op: "dissociate", Err: err}
+			}
+		}
+	}
+	return firstErr
+}
+
 func (w *Watcher) associateFile(path string, stat os.FileInfo) error {
 	fobj := buildFileObj(path, stat)
 	w.watch(path, &fobj)
@@ -308,7 +805,13 @@ func (w *Watcher) associateFile(path string, stat os.FileInfo) error {
 	mode := C.FILE_MODIFIED | C.FILE_ATTRIB | C.FILE_NOFOLLOW
 
 	_, err := C.port_associate(w.port, C.PORT_SOURCE_FILE, C.from_file_obj(&fobj), C.int(mode), unsafe.Pointer(&finfo))
-	return err
+	if err != nil {
+		if errno, ok := err.(syscall.Errno); ok && (errno == syscall.EMFILE || errno == syscall.ENFILE) {
+			return &WatchLimitError{Path: path, Err: err}
+		}
+		return &PathError{Path: path, Op: "associate", Err: err}
+	}
+	return nil
 }
 
 func (w *Watcher) dissociateFile(path string, stat os.FileInfo) error {
@@ -317,8 +820,10 @@ func (w *Watcher) dissociateFile(path string, stat os.FileInfo) error {
 	}
 	fobj := w.unwatch(path)
 
-	_, err := C.port_dissociate(w.port, C.PORT_SOURCE_FILE, C.from_file_obj(fobj))
-	return err
+	if _, err := C.port_dissociate(w.port, C.PORT_SOURCE_FILE, C.from_file_obj(fobj)); err != nil {
+		return &PathError{Path: path, Op: "dissociate", Err: err}
+	}
+	return nil
 }
 
 func buildFileObj(path string, stat os.FileInfo) C.struct_file_obj {
@@ -0,0 +1,67 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// globPrefixDir returns the longest directory in pattern that contains no
+// wildcard segment, so callers can watch that single directory instead of
+// re-walking on every change.
+func globPrefixDir(pattern string) string {
+	segs := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+
+	var prefix []string
+	for _, s := range segs {
+		if s == "**" || strings.ContainsAny(s, "*?[") {
+			break
+		}
+		prefix = append(prefix, s)
+	}
+
+	if len(prefix) == 0 {
+		return "."
+	}
+	dir := strings.Join(prefix, string(filepath.Separator))
+	if filepath.IsAbs(pattern) && !filepath.IsAbs(dir) {
+		dir = string(filepath.Separator) + dir
+	}
+	return dir
+}
+
+// globMatch reports whether path matches pattern. Patterns use
+// doublestar-style syntax: "*" and "?" match within a single path segment
+// (as path/filepath.Match does), and "**" matches zero or more whole
+// segments.
+func globMatch(pattern, path string) bool {
+	return matchSegments(
+		strings.Split(filepath.Clean(pattern), string(filepath.Separator)),
+		strings.Split(filepath.Clean(path), string(filepath.Separator)),
+	)
+}
+
+func matchSegments(pat, path []string) bool {
+	if len(pat) == 0 {
+		return len(path) == 0
+	}
+	if pat[0] == "**" {
+		if matchSegments(pat[1:], path) {
+			return true
+		}
+		if len(path) == 0 {
+			return false
+		}
+		return matchSegments(pat, path[1:])
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, err := filepath.Match(pat[0], path[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pat[1:], path[1:])
+}
@@ -0,0 +1,48 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import "testing"
+
+func TestGlobPrefixDir(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    string
+	}{
+		{"cni/net.d/*.conflist", "cni/net.d"},
+		{"**/*.conf", "."},
+		{"cni/net.d/**/*.json", "cni/net.d"},
+		{"*.conf", "."},
+		{"/etc/cni/net.d/*.conflist", "/etc/cni/net.d"},
+	}
+	for _, c := range cases {
+		if got := globPrefixDir(c.pattern); got != c.want {
+			t.Errorf("globPrefixDir(%q) = %q, want %q", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"cni/net.d/*.conflist", "cni/net.d/10-flannel.conflist", true},
+		{"cni/net.d/*.conflist", "cni/net.d/sub/10-flannel.conflist", false},
+		{"cni/net.d/*.conflist", "cni/net.d/10-flannel.conf", false},
+		{"**/*.conf", "a/b/c/x.conf", true},
+		{"**/*.conf", "x.conf", true},
+		{"**/*.conf", "x.json", false},
+		{"cni/net.d/**/*.json", "cni/net.d/sub/deep/x.json", true},
+		{"cni/net.d/**/*.json", "cni/net.d/x.json", true},
+		{"cni/net.d/**/*.json", "other/x.json", false},
+	}
+	for _, c := range cases {
+		if got := globMatch(c.pattern, c.path); got != c.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
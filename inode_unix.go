@@ -0,0 +1,22 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin freebsd openbsd netbsd dragonfly solaris
+
+package fsnotify
+
+import (
+	"os"
+	"syscall"
+)
+
+// inodeOf returns the inode number backing info, used by PollingWatcher to
+// detect a path being replaced (e.g. an atomic rename-over-target) even when
+// mtime and size happen to match.
+func inodeOf(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,296 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileWatcher is the interface implemented by the PollingWatcher fallback
+// and by the adapter around the native, OS-backed Watcher that New returns
+// (*Watcher itself does not implement it). Code that wants to run
+// unmodified on platforms (or filesystems, such as NFS, FUSE and overlayfs)
+// where the native event source is unavailable or unreliable should depend
+// on this interface instead of *Watcher directly.
+type FileWatcher interface {
+	Events() <-chan Event
+	Errors() <-chan error
+	Add(path string) error
+	Remove(path string) error
+	Close() error
+}
+
+// Events returns the channel events are delivered on.
+func (w *PollingWatcher) Events() <-chan Event { return w.events }
+
+// Errors returns the channel errors are delivered on.
+func (w *PollingWatcher) Errors() <-chan error { return w.errors }
+
+// fileState is the subset of os.FileInfo the PollingWatcher caches in order
+// to detect changes between polls.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	mode    os.FileMode
+	inode   uint64
+}
+
+// PollingWatcher is a FileWatcher that stats watched paths on a fixed
+// interval and synthesizes Create, Write, Chmod, Remove and Rename events by
+// diffing against the previous poll. It is intended as a fallback for
+// platforms and filesystems where a native, event-driven Watcher is
+// unavailable or unreliable.
+type PollingWatcher struct {
+	events chan Event
+	errors chan error
+
+	interval time.Duration
+
+	mu    sync.Mutex
+	paths map[string]fileState
+	dirs  map[string]map[string]fileState
+
+	done     chan struct{}
+	doneResp chan struct{}
+}
+
+// NewPollingWatcher creates a PollingWatcher that checks watched paths every
+// interval.
+func NewPollingWatcher(interval time.Duration) *PollingWatcher {
+	w := &PollingWatcher{
+		events:   make(chan Event),
+		errors:   make(chan error),
+		interval: interval,
+		paths:    make(map[string]fileState),
+		dirs:     make(map[string]map[string]fileState),
+		done:     make(chan struct{}),
+		doneResp: make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func stateOf(info os.FileInfo) fileState {
+	return fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		mode:    info.Mode(),
+		inode:   inodeOf(info),
+	}
+}
+
+func (s fileState) changed(o fileState) (write, chmod bool) {
+	write = s.modTime != o.modTime || s.size != o.size || s.inode != o.inode
+	chmod = s.mode != o.mode
+	return write, chmod
+}
+
+// Add starts watching the named file or directory on the polling interval.
+func (w *PollingWatcher) Add(path string) error {
+	if w.isClosed() {
+		return errors.New("polling watcher already closed")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paths[path] = stateOf(info)
+	if info.IsDir() {
+		children, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		snapshot := make(map[string]fileState, len(children))
+		for _, c := range children {
+			cinfo, err := c.Info()
+			if err != nil {
+				continue
+			}
+			snapshot[c.Name()] = stateOf(cinfo)
+		}
+		w.dirs[path] = snapshot
+	}
+	return nil
+}
+
+// Remove stops watching the named file or directory.
+func (w *PollingWatcher) Remove(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.paths, path)
+	delete(w.dirs, path)
+	return nil
+}
+
+func (w *PollingWatcher) isClosed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops polling and closes the Events and Errors channels.
+func (w *PollingWatcher) Close() error {
+	if w.isClosed() {
+		return nil
+	}
+	close(w.done)
+	<-w.doneResp
+	return nil
+}
+
+func (w *PollingWatcher) sendEvent(e Event) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *PollingWatcher) sendError(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *PollingWatcher) loop() {
+	defer close(w.doneResp)
+	defer close(w.errors)
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if !w.poll() {
+				return
+			}
+		}
+	}
+}
+
+// poll checks every watched path once, reporting whether the watcher is
+// still open.
+func (w *PollingWatcher) poll() bool {
+	w.mu.Lock()
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	w.mu.Unlock()
+
+	for _, path := range paths {
+		if !w.pollPath(path) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *PollingWatcher) pollPath(path string) bool {
+	w.mu.Lock()
+	prev, tracked := w.paths[path]
+	_, isDir := w.dirs[path]
+	w.mu.Unlock()
+	if !tracked {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		w.mu.Lock()
+		delete(w.paths, path)
+		delete(w.dirs, path)
+		w.mu.Unlock()
+		return w.sendEvent(Event{path, Remove})
+	}
+	if err != nil {
+		return w.sendError(err)
+	}
+
+	cur := stateOf(info)
+	if cur != prev {
+		write, chmod := cur.changed(prev)
+		if cur.inode != prev.inode && prev.inode != 0 {
+			if !w.sendEvent(Event{path, Rename}) {
+				return false
+			}
+		} else if write && !isDir {
+			// A child create/remove bumps a directory's own mtime, but the
+			// native watchers never report Write on a directory - only
+			// pollDirectory's own Create/Remove diff - so skip it here too.
+			if !w.sendEvent(Event{path, Write}) {
+				return false
+			}
+		}
+		if chmod {
+			if !w.sendEvent(Event{path, Chmod}) {
+				return false
+			}
+		}
+		w.mu.Lock()
+		w.paths[path] = cur
+		w.mu.Unlock()
+	}
+
+	if isDir {
+		return w.pollDirectory(path)
+	}
+	return true
+}
+
+func (w *PollingWatcher) pollDirectory(path string) bool {
+	children, err := os.ReadDir(path)
+	if err != nil {
+		return w.sendError(err)
+	}
+
+	seen := make(map[string]fileState, len(children))
+	for _, c := range children {
+		info, err := c.Info()
+		if err != nil {
+			continue
+		}
+		seen[c.Name()] = stateOf(info)
+	}
+
+	w.mu.Lock()
+	prev := w.dirs[path]
+	w.dirs[path] = seen
+	w.mu.Unlock()
+
+	for name := range seen {
+		if _, ok := prev[name]; !ok {
+			if !w.sendEvent(Event{filepath.Join(path, name), Create}) {
+				return false
+			}
+		}
+	}
+	for name := range prev {
+		if _, ok := seen[name]; !ok {
+			if !w.sendEvent(Event{filepath.Join(path, name), Remove}) {
+				return false
+			}
+		}
+	}
+	return true
+}
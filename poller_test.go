@@ -0,0 +1,97 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fsnotify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitForEvent(t *testing.T, w *PollingWatcher) Event {
+	t.Helper()
+	select {
+	case e := <-w.Events():
+		return e
+	case err := <-w.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestPollingWatcherDetectsWriteAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "watched.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewPollingWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	if err := w.Add(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond) // let the baseline poll settle first
+
+	if err := os.WriteFile(path, []byte("v2, longer than v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if e := waitForEvent(t, w); e.Name != path || e.Op&Write == 0 {
+		t.Fatalf("got %+v, want a Write event for %s", e, path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if e := waitForEvent(t, w); e.Name != path || e.Op&Remove == 0 {
+		t.Fatalf("got %+v, want a Remove event for %s", e, path)
+	}
+}
+
+func TestPollingWatcherDetectsDirectoryCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	w := NewPollingWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	newFile := filepath.Join(dir, "new.txt")
+	if err := os.WriteFile(newFile, []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := waitForEvent(t, w); e.Name != newFile || e.Op&Create == 0 {
+		t.Fatalf("got %+v, want a Create event for %s", e, newFile)
+	}
+}
+
+func TestFileStateChanged(t *testing.T) {
+	base := fileState{modTime: time.Unix(100, 0), size: 10, mode: 0644, inode: 1}
+
+	write, chmod := base.changed(base)
+	if write || chmod {
+		t.Fatalf("identical states reported a change: write=%v chmod=%v", write, chmod)
+	}
+
+	bigger := base
+	bigger.size = 20
+	if write, _ := bigger.changed(base); !write {
+		t.Fatal("size change wasn't reported as a write")
+	}
+
+	chmoded := base
+	chmoded.mode = 0600
+	if _, chmod := chmoded.changed(base); !chmod {
+		t.Fatal("mode change wasn't reported as a chmod")
+	}
+}